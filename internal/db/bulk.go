@@ -0,0 +1,22 @@
+package db
+
+// BulkResult reports the outcome of a bulk upsert. Upserts are unordered, so
+// a single bad document does not fail the whole batch -- it surfaces as an
+// entry in Errors, keyed by its index in the request slice, leaving the
+// caller free to retry just those rows.
+type BulkResult struct {
+	UpsertedCount int
+	ModifiedCount int
+	Errors        []BulkWriteError
+}
+
+// BulkWriteError associates a single failed document, by its index in the
+// request slice, with the error its write produced.
+type BulkWriteError struct {
+	Index int
+	Err   error
+}
+
+func (e BulkWriteError) Error() string {
+	return e.Err.Error()
+}
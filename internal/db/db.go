@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/InjectiveLabs/injective-guilds-service/internal/db/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Errors returned by DBService implementations. ErrMemberExceedCap and
+// ErrAlreadyMember are returned by every backend. ErrNotFound is returned by
+// pgimpl for a missing guild or account portfolio; mongoimpl returns the
+// driver's mongo.ErrNoDocuments in that case instead.
+var (
+	ErrNotFound        = errors.New("dberr: not found")
+	ErrMemberExceedCap = errors.New("member exceeds cap")
+	ErrAlreadyMember   = errors.New("already member")
+)
+
+// DBService is the storage-layer abstraction used by the guilds service.
+// Implementations (see mongoimpl and pgimpl) are responsible for guild, member and
+// portfolio persistence.
+type DBService interface {
+	EnsureIndex(ctx context.Context) error
+
+	AddGuild(ctx context.Context, guild *model.Guild) (*primitive.ObjectID, error)
+	DeleteGuild(ctx context.Context, guildID string) error
+	ListAllGuilds(ctx context.Context) ([]*model.Guild, error)
+	GetSingleGuild(ctx context.Context, guildID string) (*model.Guild, error)
+
+	AddMember(ctx context.Context, guildID string, address model.Address, isDefaultMember bool) error
+	RemoveMember(ctx context.Context, guildID string, address model.Address) error
+	// ListGuildMembers returns a page of members and, when more results are
+	// available, a nextPageToken to pass back via MemberFilter.PageToken.
+	ListGuildMembers(ctx context.Context, filter model.MemberFilter) (members []*model.GuildMember, nextPageToken string, err error)
+
+	// AddGuildPortfolios upserts portfolios keyed by (guild_id, updated_at),
+	// so re-submitting a snapshot for a key the caller already sent (e.g.
+	// after a scraper retry) overwrites the stored snapshot in place rather
+	// than inserting a duplicate or failing on the duplicate key.
+	AddGuildPortfolios(ctx context.Context, portfolios []*model.GuildPortfolio) (*BulkResult, error)
+	// ListGuildPortfolios returns a page of snapshots and, when more results
+	// are available, a nextPageToken to pass back via GuildPortfoliosFilter.PageToken.
+	ListGuildPortfolios(ctx context.Context, filter model.GuildPortfoliosFilter) (portfolios []*model.GuildPortfolio, nextPageToken string, err error)
+
+	GetAccountPortfolio(ctx context.Context, address model.Address) (*model.AccountPortfolio, error)
+	// AddAccountPortfolios upserts portfolios keyed by (injective_address,
+	// updated_at); see AddGuildPortfolios.
+	AddAccountPortfolios(ctx context.Context, portfolios []*model.AccountPortfolio) (*BulkResult, error)
+	// ListAccountPortfolios returns a page of snapshots and, when more results
+	// are available, a nextPageToken to pass back via AccountPortfoliosFilter.PageToken.
+	ListAccountPortfolios(ctx context.Context, filter model.AccountPortfoliosFilter) (portfolios []*model.AccountPortfolio, nextPageToken string, err error)
+
+	// GetGuildLeaderboard ranks a guild's members by the change in their
+	// denom balance between startTime and endTime, returning at most topN
+	// entries, highest change first.
+	GetGuildLeaderboard(
+		ctx context.Context,
+		guildID string,
+		denom string,
+		startTime, endTime time.Time,
+		topN int64,
+	) ([]*model.LeaderboardEntry, error)
+
+	// GetGuildPortfolioSeries downsamples a guild's portfolio snapshots
+	// between startTime and endTime into OHLC-style buckets of the given
+	// width, computed server-side via aggregation.
+	GetGuildPortfolioSeries(
+		ctx context.Context,
+		guildID string,
+		bucket time.Duration,
+		startTime, endTime time.Time,
+	) ([]*model.PortfolioBucket, error)
+
+	// Subscribe streams mutation events for the requested kinds on a
+	// best-effort basis: the returned channel is closed once ctx is
+	// cancelled. Delivery guarantees across a subscriber restart or
+	// reconnect are backend-specific: implementations backed by a durable,
+	// resumable log (e.g. the Mongo change stream backend) resume from
+	// their last acknowledged position, so a subscriber may see a
+	// duplicate event around a restart but should not miss one.
+	// Implementations without such a log (e.g. the Postgres LISTEN/NOTIFY
+	// backend) are at-most-once: any mutation between a disconnect and
+	// re-subscribe is not redelivered. See each implementation's Subscribe
+	// doc for its actual guarantee.
+	Subscribe(ctx context.Context, kinds []EventKind) (<-chan Event, error)
+
+	Disconnect(ctx context.Context) error
+}
@@ -0,0 +1,37 @@
+// Package dbfactory selects and constructs a db.DBService for the driver an
+// operator configures at startup. It lives outside package db to avoid an
+// import cycle, since it must import every backend implementation.
+package dbfactory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/InjectiveLabs/injective-guilds-service/internal/db"
+	"github.com/InjectiveLabs/injective-guilds-service/internal/db/mongoimpl"
+	"github.com/InjectiveLabs/injective-guilds-service/internal/db/pgimpl"
+)
+
+// Driver identifies which backend New should construct.
+type Driver string
+
+const (
+	DriverMongo    Driver = "mongo"
+	DriverPostgres Driver = "postgres"
+)
+
+// New connects to the configured backend and returns a ready-to-use
+// db.DBService. dbName and retentionPolicy are only meaningful for the mongo
+// driver; for postgres the database name is expected to already be part of
+// dsn and retention is configured via the operator's own TTL policy on the
+// relational tables.
+func New(ctx context.Context, driver Driver, dsn, dbName string, retentionPolicy mongoimpl.RetentionPolicy) (db.DBService, error) {
+	switch driver {
+	case DriverMongo:
+		return mongoimpl.NewService(ctx, dsn, dbName, retentionPolicy)
+	case DriverPostgres:
+		return pgimpl.NewService(ctx, dsn)
+	default:
+		return nil, fmt.Errorf("unknown db driver: %q", driver)
+	}
+}
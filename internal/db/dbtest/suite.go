@@ -0,0 +1,237 @@
+// Package dbtest holds the behavioral test suite shared by every
+// db.DBService implementation. mongoimpl and pgimpl each have a thin
+// integration test that builds a real backend instance and hands it to
+// RunSuite, so a regression in one backend's semantics (e.g. pagination,
+// member-cap enforcement, portfolio upsert dedup) can't silently diverge
+// from the other.
+package dbtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/InjectiveLabs/injective-guilds-service/internal/db"
+	"github.com/InjectiveLabs/injective-guilds-service/internal/db/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RunSuite exercises the full db.DBService contract against svc. Callers are
+// responsible for giving each call to RunSuite a backend pointed at an empty
+// database/schema: the suite only deletes the guilds it creates (and, through
+// DeleteGuild, their members and portfolios) and does not otherwise reset it.
+func RunSuite(t *testing.T, svc db.DBService) {
+	t.Run("EnsureIndex", func(t *testing.T) { testEnsureIndex(t, svc) })
+	t.Run("GuildLifecycle", func(t *testing.T) { testGuildLifecycle(t, svc) })
+	t.Run("MemberLifecycle", func(t *testing.T) { testMemberLifecycle(t, svc) })
+	t.Run("GuildPortfolioPagination", func(t *testing.T) { testGuildPortfolioPagination(t, svc) })
+	t.Run("AccountPortfolioUpsertIsIdempotent", func(t *testing.T) { testAccountPortfolioUpsertIsIdempotent(t, svc) })
+}
+
+func testEnsureIndex(t *testing.T, svc db.DBService) {
+	ctx := context.Background()
+	if err := svc.EnsureIndex(ctx); err != nil {
+		t.Fatalf("EnsureIndex: %v", err)
+	}
+	// must be safe to run twice, e.g. on every service startup
+	if err := svc.EnsureIndex(ctx); err != nil {
+		t.Fatalf("EnsureIndex (second run): %v", err)
+	}
+}
+
+func testGuildLifecycle(t *testing.T, svc db.DBService) {
+	ctx := context.Background()
+
+	guild := &model.Guild{Name: "alpha", Description: "alpha guild", Capacity: 5}
+	guildID, err := svc.AddGuild(ctx, guild)
+	if err != nil {
+		t.Fatalf("AddGuild: %v", err)
+	}
+
+	got, err := svc.GetSingleGuild(ctx, guildID.Hex())
+	if err != nil {
+		t.Fatalf("GetSingleGuild: %v", err)
+	}
+	if got.Name != guild.Name {
+		t.Fatalf("GetSingleGuild: got name %q, want %q", got.Name, guild.Name)
+	}
+
+	all, err := svc.ListAllGuilds(ctx)
+	if err != nil {
+		t.Fatalf("ListAllGuilds: %v", err)
+	}
+	if !containsGuildID(all, *guildID) {
+		t.Fatalf("ListAllGuilds: missing guild %s", guildID.Hex())
+	}
+
+	if err := svc.DeleteGuild(ctx, guildID.Hex()); err != nil {
+		t.Fatalf("DeleteGuild: %v", err)
+	}
+	if _, err := svc.GetSingleGuild(ctx, guildID.Hex()); err == nil {
+		t.Fatalf("GetSingleGuild: expected error after DeleteGuild, got nil")
+	}
+}
+
+func containsGuildID(guilds []*model.Guild, id primitive.ObjectID) bool {
+	for _, g := range guilds {
+		if g.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+func testMemberLifecycle(t *testing.T, svc db.DBService) {
+	ctx := context.Background()
+
+	guildID, err := svc.AddGuild(ctx, &model.Guild{Name: "members-guild", Capacity: 1})
+	if err != nil {
+		t.Fatalf("AddGuild: %v", err)
+	}
+	defer svc.DeleteGuild(ctx, guildID.Hex())
+
+	addr := model.Address("inj1member")
+	if err := svc.AddMember(ctx, guildID.Hex(), addr, false); err != nil {
+		t.Fatalf("AddMember: %v", err)
+	}
+
+	if err := svc.AddMember(ctx, guildID.Hex(), addr, false); err != db.ErrAlreadyMember {
+		t.Fatalf("AddMember (duplicate): got %v, want db.ErrAlreadyMember", err)
+	}
+
+	overCap := model.Address("inj1overcap")
+	if err := svc.AddMember(ctx, guildID.Hex(), overCap, false); err != db.ErrMemberExceedCap {
+		t.Fatalf("AddMember (over capacity): got %v, want db.ErrMemberExceedCap", err)
+	}
+
+	guildIDHex := guildID.Hex()
+	members, _, err := svc.ListGuildMembers(ctx, model.MemberFilter{GuildID: &guildIDHex})
+	if err != nil {
+		t.Fatalf("ListGuildMembers: %v", err)
+	}
+	if len(members) != 1 || members[0].InjectiveAddress != addr.String() {
+		t.Fatalf("ListGuildMembers: got %+v, want one member %q", members, addr)
+	}
+
+	if err := svc.RemoveMember(ctx, guildID.Hex(), addr); err != nil {
+		t.Fatalf("RemoveMember: %v", err)
+	}
+
+	members, _, err = svc.ListGuildMembers(ctx, model.MemberFilter{GuildID: &guildIDHex})
+	if err != nil {
+		t.Fatalf("ListGuildMembers (after remove): %v", err)
+	}
+	if len(members) != 0 {
+		t.Fatalf("ListGuildMembers (after remove): got %+v, want none", members)
+	}
+}
+
+func testGuildPortfolioPagination(t *testing.T, svc db.DBService) {
+	ctx := context.Background()
+
+	guildID, err := svc.AddGuild(ctx, &model.Guild{Name: "portfolio-guild", Capacity: 10})
+	if err != nil {
+		t.Fatalf("AddGuild: %v", err)
+	}
+	defer svc.DeleteGuild(ctx, guildID.Hex())
+
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	const snapshotCount = 5
+	portfolios := make([]*model.GuildPortfolio, snapshotCount)
+	for i := range portfolios {
+		portfolios[i] = &model.GuildPortfolio{
+			GuildID:    *guildID,
+			UpdatedAt:  base.Add(time.Duration(i) * time.Minute),
+			TotalValue: "100",
+			Balances:   []model.Balance{{Denom: "inj", Amount: "100"}},
+		}
+	}
+	if _, err := svc.AddGuildPortfolios(ctx, portfolios); err != nil {
+		t.Fatalf("AddGuildPortfolios: %v", err)
+	}
+
+	var (
+		seen      []*model.GuildPortfolio
+		pageToken string
+		limit     = int64(2)
+	)
+	for {
+		page, next, err := svc.ListGuildPortfolios(ctx, model.GuildPortfoliosFilter{
+			GuildID:   guildID.Hex(),
+			Limit:     &limit,
+			PageToken: pageToken,
+		})
+		if err != nil {
+			t.Fatalf("ListGuildPortfolios: %v", err)
+		}
+		seen = append(seen, page...)
+		if next == "" {
+			break
+		}
+		pageToken = next
+	}
+
+	if len(seen) != snapshotCount {
+		t.Fatalf("ListGuildPortfolios: paged through %d snapshots, want %d", len(seen), snapshotCount)
+	}
+	for i := 1; i < len(seen); i++ {
+		if seen[i].UpdatedAt.After(seen[i-1].UpdatedAt) {
+			t.Fatalf("ListGuildPortfolios: page %d out of order (%v after %v)", i, seen[i].UpdatedAt, seen[i-1].UpdatedAt)
+		}
+	}
+
+	zero := int64(0)
+	if _, _, err := svc.ListGuildPortfolios(ctx, model.GuildPortfoliosFilter{GuildID: guildID.Hex(), Limit: &zero}); err != nil {
+		t.Fatalf("ListGuildPortfolios (Limit=0): %v", err)
+	}
+}
+
+func testAccountPortfolioUpsertIsIdempotent(t *testing.T, svc db.DBService) {
+	ctx := context.Background()
+
+	guildID, err := svc.AddGuild(ctx, &model.Guild{Name: "upsert-guild", Capacity: 10})
+	if err != nil {
+		t.Fatalf("AddGuild: %v", err)
+	}
+	defer svc.DeleteGuild(ctx, guildID.Hex())
+
+	addr := model.Address("inj1upsert")
+	snapshot := &model.AccountPortfolio{
+		InjectiveAddress: addr.String(),
+		GuildID:          *guildID,
+		UpdatedAt:        time.Now().Add(-time.Minute).Truncate(time.Second),
+		TotalValue:       "100",
+		Balances:         []model.Balance{{Denom: "inj", Amount: "100"}},
+	}
+
+	first, err := svc.AddAccountPortfolios(ctx, []*model.AccountPortfolio{snapshot})
+	if err != nil {
+		t.Fatalf("AddAccountPortfolios: %v", err)
+	}
+	if first.UpsertedCount != 1 {
+		t.Fatalf("AddAccountPortfolios: got UpsertedCount %d, want 1", first.UpsertedCount)
+	}
+
+	retry := &model.AccountPortfolio{
+		InjectiveAddress: snapshot.InjectiveAddress,
+		GuildID:          snapshot.GuildID,
+		UpdatedAt:        snapshot.UpdatedAt,
+		TotalValue:       "200",
+		Balances:         []model.Balance{{Denom: "inj", Amount: "200"}},
+	}
+	second, err := svc.AddAccountPortfolios(ctx, []*model.AccountPortfolio{retry})
+	if err != nil {
+		t.Fatalf("AddAccountPortfolios (retry): %v", err)
+	}
+	if second.ModifiedCount != 1 || second.UpsertedCount != 0 {
+		t.Fatalf("AddAccountPortfolios (retry): got %+v, want one in-place modification", second)
+	}
+
+	got, err := svc.GetAccountPortfolio(ctx, addr)
+	if err != nil {
+		t.Fatalf("GetAccountPortfolio: %v", err)
+	}
+	if got.TotalValue != "200" {
+		t.Fatalf("GetAccountPortfolio: got TotalValue %q, want %q (retry should overwrite, not duplicate)", got.TotalValue, "200")
+	}
+}
@@ -0,0 +1,32 @@
+package db
+
+import "encoding/json"
+
+// EventKind identifies which collection/entity an Event was derived from.
+type EventKind string
+
+const (
+	EventKindGuild          EventKind = "guild"
+	EventKindMember         EventKind = "member"
+	EventKindGuildPortfolio EventKind = "guild_portfolio"
+)
+
+// EventType is the kind of mutation that produced an Event.
+type EventType string
+
+const (
+	EventTypeInsert EventType = "insert"
+	EventTypeUpdate EventType = "update"
+	EventTypeDelete EventType = "delete"
+)
+
+// Event is a single mutation observed by Subscribe. Data holds the affected
+// document (when available) as opaque JSON so the event bus stays backend
+// agnostic. For EventKindGuildPortfolio on the Postgres backend, Data only
+// carries the row's identity columns rather than the full row; see
+// pgimpl/changenotify.go.
+type Event struct {
+	Kind EventKind
+	Type EventType
+	Data json.RawMessage
+}
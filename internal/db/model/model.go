@@ -0,0 +1,118 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Address represents an Injective chain bech32 address.
+type Address string
+
+// String returns the bech32 string form of the address.
+func (a Address) String() string {
+	return string(a)
+}
+
+// Guild represents a pool of accounts whose portfolio performance is tracked together.
+type Guild struct {
+	Id          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        string             `bson:"name" json:"name"`
+	Description string             `bson:"description" json:"description"`
+	MemberCount int                `bson:"member_count" json:"member_count"`
+	Capacity    int                `bson:"capacity" json:"capacity"`
+}
+
+// GuildMember represents a single account's membership in a guild.
+type GuildMember struct {
+	ID                   primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	GuildID              primitive.ObjectID `bson:"guild_id" json:"guild_id"`
+	InjectiveAddress     string             `bson:"injective_address" json:"injective_address"`
+	IsDefaultGuildMember bool               `bson:"is_default_guild_member" json:"is_default_guild_member"`
+	Since                time.Time          `bson:"since" json:"since"`
+}
+
+// MemberFilter narrows ListGuildMembers to members matching the given guild,
+// default-membership flag and/or address. Nil fields are not filtered on.
+//
+// PageToken, when set, resumes a previous listing from the cursor returned as
+// NextPageToken by the prior call.
+type MemberFilter struct {
+	GuildID          *string
+	IsDefaultMember  *bool
+	InjectiveAddress *string
+
+	PageToken string
+	Limit     *int64
+}
+
+// Balance is a single denom/amount pair held within a portfolio snapshot.
+// Amount is kept as a decimal string to avoid floating point precision loss.
+type Balance struct {
+	Denom  string `bson:"denom" json:"denom"`
+	Amount string `bson:"amount" json:"amount"`
+}
+
+// GuildPortfolio is a single snapshot of a guild's aggregate portfolio value.
+type GuildPortfolio struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	GuildID    primitive.ObjectID `bson:"guild_id" json:"guild_id"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+	TotalValue string             `bson:"total_value" json:"total_value"`
+	Balances   []Balance          `bson:"balances" json:"balances"`
+}
+
+// GuildPortfoliosFilter narrows ListGuildPortfolios to a guild and time range.
+//
+// PageToken, when set, resumes a previous listing from the cursor returned as
+// NextPageToken by the prior call. It is an opaque value and must not be
+// constructed by callers.
+type GuildPortfoliosFilter struct {
+	GuildID   string
+	StartTime *time.Time
+	EndTime   *time.Time
+	Limit     *int64
+	PageToken string
+}
+
+// AccountPortfolio is a single snapshot of one account's portfolio value.
+type AccountPortfolio struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	InjectiveAddress string             `bson:"injective_address" json:"injective_address"`
+	GuildID          primitive.ObjectID `bson:"guild_id" json:"guild_id"`
+	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+	TotalValue       string             `bson:"total_value" json:"total_value"`
+	Balances         []Balance          `bson:"balances" json:"balances"`
+}
+
+// AccountPortfoliosFilter narrows ListAccountPortfolios to an address and time range.
+//
+// PageToken, when set, resumes a previous listing from the cursor returned as
+// NextPageToken by the prior call. It is an opaque value and must not be
+// constructed by callers.
+type AccountPortfoliosFilter struct {
+	InjectiveAddress Address
+	StartTime        *time.Time
+	EndTime          *time.Time
+	Limit            *int64
+	PageToken        string
+}
+
+// LeaderboardEntry ranks a single member's portfolio value change for one
+// denom over the requested window.
+type LeaderboardEntry struct {
+	InjectiveAddress string               `bson:"_id" json:"injective_address"`
+	StartValue       primitive.Decimal128 `bson:"start_value" json:"start_value"`
+	EndValue         primitive.Decimal128 `bson:"end_value" json:"end_value"`
+	Change           primitive.Decimal128 `bson:"change" json:"change"`
+}
+
+// PortfolioBucket is a single OHLC-style downsampled time bucket of a guild's
+// total portfolio value, produced by GetGuildPortfolioSeries.
+type PortfolioBucket struct {
+	BucketStart time.Time            `bson:"_id" json:"bucket_start"`
+	Open        primitive.Decimal128 `bson:"open" json:"open"`
+	High        primitive.Decimal128 `bson:"high" json:"high"`
+	Low         primitive.Decimal128 `bson:"low" json:"low"`
+	Close       primitive.Decimal128 `bson:"close" json:"close"`
+}
@@ -0,0 +1,34 @@
+package mongoimpl
+
+import (
+	"errors"
+
+	"github.com/InjectiveLabs/injective-guilds-service/internal/db"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// toBulkResult translates a *mongo.BulkWriteResult/error pair from an
+// unordered BulkWrite into a db.BulkResult. Per-document write errors are
+// collected onto the result instead of failing the call outright; only
+// request-level errors (e.g. a dropped connection) are returned as err.
+func toBulkResult(res *mongo.BulkWriteResult, writeErr error) (*db.BulkResult, error) {
+	result := &db.BulkResult{}
+	if res != nil {
+		result.UpsertedCount = int(res.UpsertedCount)
+		result.ModifiedCount = int(res.ModifiedCount)
+	}
+
+	if writeErr == nil {
+		return result, nil
+	}
+
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(writeErr, &bulkErr) {
+		return result, writeErr
+	}
+
+	for _, we := range bulkErr.WriteErrors {
+		result.Errors = append(result.Errors, db.BulkWriteError{Index: we.Index, Err: we})
+	}
+	return result, nil
+}
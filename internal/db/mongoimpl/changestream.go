@@ -0,0 +1,349 @@
+package mongoimpl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/InjectiveLabs/injective-guilds-service/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// eventBufferSize bounds how many events Subscribe will buffer for a slow
+// consumer before it starts blocking the underlying change stream.
+const eventBufferSize = 64
+
+// oplogFallbackThreshold is how many consecutive change-stream failures on a
+// collection trigger a fallback to tailing the replica set oplog directly,
+// for deployments where change streams are unavailable (e.g. the driver
+// can't establish one against a given topology) or keep failing to resume.
+const oplogFallbackThreshold = 3
+
+// resumeTokenDoc persists the last resume token acknowledged for a single
+// watched collection, so a restarted Subscribe call picks up where the
+// previous one left off instead of replaying from the start of the oplog.
+type resumeTokenDoc struct {
+	ID    string   `bson:"_id"`
+	Token bson.Raw `bson:"token"`
+}
+
+// oplogPositionDoc persists the timestamp of the last oplog entry consumed
+// by the oplog-tailing fallback for a single watched collection, keyed
+// separately from resumeTokenDoc since a change stream resume token and an
+// oplog timestamp aren't interchangeable.
+type oplogPositionDoc struct {
+	ID string              `bson:"_id"`
+	TS primitive.Timestamp `bson:"ts"`
+}
+
+// oplogStateID is the _id under which a collection's oplog tailing position
+// is stored, distinct from its change stream resume token's _id.
+func oplogStateID(kind db.EventKind) string {
+	return string(kind) + "_oplog"
+}
+
+// changeStreamSource pairs a single EventKind with the collection backing
+// it; toEvent translates that collection's change events into a db.Event.
+type changeStreamSource struct {
+	kind       db.EventKind
+	collection *mongo.Collection
+}
+
+func (s *MongoImpl) changeStreamSources(kinds []db.EventKind) []changeStreamSource {
+	byKind := map[db.EventKind]*mongo.Collection{
+		db.EventKindGuild:          s.guildCollection,
+		db.EventKindMember:         s.memberCollection,
+		db.EventKindGuildPortfolio: s.guildPortfolioCollection,
+	}
+
+	sources := make([]changeStreamSource, 0, len(kinds))
+	for _, kind := range kinds {
+		if coll, ok := byKind[kind]; ok {
+			sources = append(sources, changeStreamSource{kind: kind, collection: coll})
+		}
+	}
+	return sources
+}
+
+// Subscribe watches the collections backing kinds via MongoDB change streams
+// and emits a db.Event per insert/update/delete. Each collection is watched
+// on its own goroutine so a resume or error on one does not stall the rest.
+// The returned channel is closed once ctx is cancelled or every watched
+// collection has stopped producing events.
+func (s *MongoImpl) Subscribe(ctx context.Context, kinds []db.EventKind) (<-chan db.Event, error) {
+	sources := s.changeStreamSources(kinds)
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("subscribe: no watchable collection for kinds %v", kinds)
+	}
+
+	out := make(chan db.Event, eventBufferSize)
+
+	var wg sync.WaitGroup
+	wg.Add(len(sources))
+	for _, source := range sources {
+		source := source
+		go func() {
+			defer wg.Done()
+			s.watchCollection(ctx, source, out)
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out, nil
+}
+
+// watchCollection runs a single collection's change stream until ctx is
+// cancelled, restarting it from the last saved resume token whenever the
+// stream errors out. After oplogFallbackThreshold consecutive failures it
+// falls back to tailing the replica set oplog directly until that, too,
+// fails, then goes back to retrying the change stream.
+func (s *MongoImpl) watchCollection(ctx context.Context, source changeStreamSource, out chan<- db.Event) {
+	failures := 0
+	for ctx.Err() == nil {
+		err := s.runChangeStream(ctx, source, out)
+		if err == nil || ctx.Err() != nil {
+			failures = 0
+			continue
+		}
+		failures++
+
+		if failures >= oplogFallbackThreshold {
+			if err := s.tailOplog(ctx, source, out); err != nil && ctx.Err() == nil {
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+					return
+				}
+			}
+			failures = 0
+			continue
+		}
+
+		// Transient errors (network blips, stream invalidation) are
+		// retried after a short backoff; a resumable error still
+		// resumes from the last saved token on the next attempt.
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *MongoImpl) runChangeStream(ctx context.Context, source changeStreamSource, out chan<- db.Event) error {
+	streamOpts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+
+	resumeToken, err := s.loadResumeToken(ctx, source.kind)
+	if err != nil {
+		return fmt.Errorf("load resume token for %s: %w", source.kind, err)
+	}
+	if resumeToken != nil {
+		streamOpts.SetResumeAfter(resumeToken)
+	}
+
+	stream, err := source.collection.Watch(ctx, mongo.Pipeline{}, streamOpts)
+	if err != nil {
+		return fmt.Errorf("watch %s: %w", source.kind, err)
+	}
+	defer stream.Close(ctx)
+
+	for stream.Next(ctx) {
+		var raw bson.Raw
+		if err := stream.Decode(&raw); err != nil {
+			return fmt.Errorf("decode change event for %s: %w", source.kind, err)
+		}
+
+		event, err := s.toEvent(source.kind, raw)
+		if err != nil {
+			return fmt.Errorf("translate change event for %s: %w", source.kind, err)
+		}
+
+		select {
+		case out <- event:
+		case <-ctx.Done():
+			return nil
+		}
+
+		if err := s.saveResumeToken(ctx, source.kind, stream.ResumeToken()); err != nil {
+			return fmt.Errorf("save resume token for %s: %w", source.kind, err)
+		}
+	}
+
+	return stream.Err()
+}
+
+// toEvent converts a raw Mongo change stream document into a backend
+// agnostic db.Event.
+func (s *MongoImpl) toEvent(kind db.EventKind, raw bson.Raw) (db.Event, error) {
+	var change struct {
+		OperationType string   `bson:"operationType"`
+		FullDocument  bson.Raw `bson:"fullDocument"`
+	}
+	if err := bson.Unmarshal(raw, &change); err != nil {
+		return db.Event{}, err
+	}
+
+	eventType, ok := operationToEventType(change.OperationType)
+	if !ok {
+		return db.Event{}, fmt.Errorf("unsupported operation type %q", change.OperationType)
+	}
+
+	var data json.RawMessage
+	if len(change.FullDocument) > 0 {
+		asJSON, err := bson.MarshalExtJSON(change.FullDocument, true, false)
+		if err != nil {
+			return db.Event{}, err
+		}
+		data = asJSON
+	}
+
+	return db.Event{Kind: kind, Type: eventType, Data: data}, nil
+}
+
+func operationToEventType(op string) (db.EventType, bool) {
+	switch op {
+	case "insert":
+		return db.EventTypeInsert, true
+	case "update", "replace":
+		return db.EventTypeUpdate, true
+	case "delete":
+		return db.EventTypeDelete, true
+	default:
+		return "", false
+	}
+}
+
+// oplogEntry is the subset of a local.oplog.rs document tailOplog cares
+// about. See https://www.mongodb.com/docs/manual/core/replica-set-oplog/.
+type oplogEntry struct {
+	Op string              `bson:"op"`
+	NS string              `bson:"ns"`
+	O  bson.Raw            `bson:"o"`
+	TS primitive.Timestamp `bson:"ts"`
+}
+
+func oplogOpToEventType(op string) (db.EventType, bool) {
+	switch op {
+	case "i":
+		return db.EventTypeInsert, true
+	case "u":
+		return db.EventTypeUpdate, true
+	case "d":
+		return db.EventTypeDelete, true
+	default:
+		return "", false
+	}
+}
+
+// tailOplog tails the replica set oplog directly for source's collection,
+// used as a fallback once runChangeStream has failed oplogFallbackThreshold
+// times in a row. It runs until ctx is cancelled or the tailable cursor
+// itself errors out, at which point watchCollection goes back to retrying
+// the change stream.
+func (s *MongoImpl) tailOplog(ctx context.Context, source changeStreamSource, out chan<- db.Event) error {
+	oplog := s.client.Database("local").Collection("oplog.rs")
+	ns := source.collection.Database().Name() + "." + source.collection.Name()
+
+	since, err := s.loadOplogTimestamp(ctx, source.kind)
+	if err != nil {
+		return fmt.Errorf("load oplog timestamp for %s: %w", source.kind, err)
+	}
+	if since == nil {
+		now := primitive.Timestamp{T: uint32(time.Now().Unix())}
+		since = &now
+	}
+
+	opts := options.Find().SetCursorType(options.TailableAwait).SetNoCursorTimeout(true)
+	filter := bson.M{"ns": ns, "ts": bson.M{"$gt": *since}}
+
+	cur, err := oplog.Find(ctx, filter, opts)
+	if err != nil {
+		return fmt.Errorf("tail oplog for %s: %w", source.kind, err)
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var entry oplogEntry
+		if err := cur.Decode(&entry); err != nil {
+			return fmt.Errorf("decode oplog entry for %s: %w", source.kind, err)
+		}
+
+		eventType, ok := oplogOpToEventType(entry.Op)
+		if !ok {
+			continue
+		}
+
+		data, err := bson.MarshalExtJSON(entry.O, true, false)
+		if err != nil {
+			return fmt.Errorf("marshal oplog entry for %s: %w", source.kind, err)
+		}
+
+		select {
+		case out <- db.Event{Kind: source.kind, Type: eventType, Data: data}:
+		case <-ctx.Done():
+			return nil
+		}
+
+		if err := s.saveOplogTimestamp(ctx, source.kind, entry.TS); err != nil {
+			return fmt.Errorf("save oplog timestamp for %s: %w", source.kind, err)
+		}
+	}
+
+	if err := cur.Err(); err != nil {
+		return fmt.Errorf("tail oplog for %s: %w", source.kind, err)
+	}
+	return ctx.Err()
+}
+
+func (s *MongoImpl) loadOplogTimestamp(ctx context.Context, kind db.EventKind) (*primitive.Timestamp, error) {
+	var doc oplogPositionDoc
+	err := s.changeStreamStateCollection.FindOne(ctx, bson.M{"_id": oplogStateID(kind)}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc.TS, nil
+}
+
+func (s *MongoImpl) saveOplogTimestamp(ctx context.Context, kind db.EventKind, ts primitive.Timestamp) error {
+	_, err := s.changeStreamStateCollection.UpdateOne(
+		ctx,
+		bson.M{"_id": oplogStateID(kind)},
+		bson.M{"$set": bson.M{"ts": ts}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (s *MongoImpl) loadResumeToken(ctx context.Context, kind db.EventKind) (bson.Raw, error) {
+	var doc resumeTokenDoc
+	err := s.changeStreamStateCollection.FindOne(ctx, bson.M{"_id": string(kind)}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.Token, nil
+}
+
+func (s *MongoImpl) saveResumeToken(ctx context.Context, kind db.EventKind, token bson.Raw) error {
+	_, err := s.changeStreamStateCollection.UpdateOne(
+		ctx,
+		bson.M{"_id": string(kind)},
+		bson.M{"$set": bson.M{"token": token}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
@@ -2,6 +2,7 @@ package mongoimpl
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"time"
@@ -15,34 +16,53 @@ import (
 )
 
 const (
-	connectionTimeout              = 30 * time.Second
-	GuildCollectionName            = "guilds"
-	MemberCollectionName           = "members"
-	AccountPortfolioCollectionName = "account_portfolios"
-	GuildPortfolioCollectionName   = "guild_portfolios"
-	DenomCollectionName            = "denoms"
+	connectionTimeout                   = 30 * time.Second
+	GuildCollectionName                 = "guilds"
+	MemberCollectionName                = "members"
+	AccountPortfolioCollectionName      = "account_portfolios"
+	GuildPortfolioCollectionName        = "guild_portfolios"
+	AccountPortfolioDailyCollectionName = "account_portfolios_daily"
+	GuildPortfolioDailyCollectionName   = "guild_portfolios_daily"
+	DenomCollectionName                 = "denoms"
+	ChangeStreamStateCollectionName     = "change_stream_state"
 )
 
-var (
-	ErrNotFound        = errors.New("dberr: not found")
-	ErrMemberExceedCap = errors.New("member exceeds cap")
-	ErrAlreadyMember   = errors.New("already member")
-)
+// RetentionPolicy configures how long raw portfolio snapshots are kept
+// before being TTL-expired, and how often they are rolled up into the daily
+// collections beforehand so historical range queries keep working.
+type RetentionPolicy struct {
+	// RawRetention is how long a raw snapshot survives before Mongo's TTL
+	// monitor deletes it. Zero disables TTL expiry (and rollup).
+	RawRetention time.Duration
+	// RollupInterval is how often the compactor rolls snapshots approaching
+	// expiry into the `*_daily` collections.
+	RollupInterval time.Duration
+}
 
 type MongoImpl struct {
-	db.DBService
-
 	client  *mongo.Client
 	session mongo.Session
 
-	guildCollection            *mongo.Collection
-	memberCollection           *mongo.Collection
-	accountPortfolioCollection *mongo.Collection
-	guildPortfolioCollection   *mongo.Collection
-	denomCollection            *mongo.Collection
+	guildCollection                 *mongo.Collection
+	memberCollection                *mongo.Collection
+	accountPortfolioCollection      *mongo.Collection
+	guildPortfolioCollection        *mongo.Collection
+	accountPortfolioDailyCollection *mongo.Collection
+	guildPortfolioDailyCollection   *mongo.Collection
+	denomCollection                 *mongo.Collection
+	changeStreamStateCollection     *mongo.Collection
+
+	retentionPolicy RetentionPolicy
+	stopCompactor   context.CancelFunc
 }
 
-func NewService(ctx context.Context, connectionURL, databaseName string) (db.DBService, error) {
+var _ db.DBService = (*MongoImpl)(nil)
+
+func NewService(
+	ctx context.Context,
+	connectionURL, databaseName string,
+	retentionPolicy RetentionPolicy,
+) (db.DBService, error) {
 	ctx, cancel := context.WithTimeout(ctx, connectionTimeout)
 	defer cancel()
 
@@ -56,15 +76,32 @@ func NewService(ctx context.Context, connectionURL, databaseName string) (db.DBS
 		return nil, fmt.Errorf("new session err: %w", err)
 	}
 
-	return &MongoImpl{
-		client:                     client,
-		session:                    session,
-		guildCollection:            client.Database(databaseName).Collection(GuildCollectionName),
-		memberCollection:           client.Database(databaseName).Collection(MemberCollectionName),
-		accountPortfolioCollection: client.Database(databaseName).Collection(AccountPortfolioCollectionName),
-		guildPortfolioCollection:   client.Database(databaseName).Collection(GuildPortfolioCollectionName),
-		denomCollection:            client.Database(databaseName).Collection(DenomCollectionName),
-	}, nil
+	database := client.Database(databaseName)
+	svc := &MongoImpl{
+		client:                          client,
+		session:                         session,
+		guildCollection:                 database.Collection(GuildCollectionName),
+		memberCollection:                database.Collection(MemberCollectionName),
+		accountPortfolioCollection:      database.Collection(AccountPortfolioCollectionName),
+		guildPortfolioCollection:        database.Collection(GuildPortfolioCollectionName),
+		accountPortfolioDailyCollection: database.Collection(AccountPortfolioDailyCollectionName),
+		guildPortfolioDailyCollection:   database.Collection(GuildPortfolioDailyCollectionName),
+		denomCollection:                 database.Collection(DenomCollectionName),
+		changeStreamStateCollection:     database.Collection(ChangeStreamStateCollectionName),
+		retentionPolicy:                 retentionPolicy,
+	}
+
+	// Start the compactor here, not left for callers to remember: a TTL
+	// index makes raw snapshots expire regardless of whether anything is
+	// rolling them up first, so enabling retention must never be separable
+	// from running the rollup that keeps historical range queries working.
+	if retentionPolicy.RawRetention > 0 && retentionPolicy.RollupInterval > 0 {
+		compactorCtx, stop := context.WithCancel(context.Background())
+		svc.stopCompactor = stop
+		svc.StartRetentionCompactor(compactorCtx)
+	}
+
+	return svc, nil
 }
 
 func makeIndex(unique bool, keys interface{}) mongo.IndexModel {
@@ -75,12 +112,59 @@ func makeIndex(unique bool, keys interface{}) mongo.IndexModel {
 	return idx
 }
 
+// makeTTLIndex builds a single-field TTL index that expires documents
+// expireAfterSeconds after the value stored in field.
+func makeTTLIndex(field string, expireAfterSeconds int32) mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys:    bson.D{{Key: field, Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(expireAfterSeconds),
+	}
+}
+
+// pageCursor is the decoded form of an opaque PageToken: the sort key of the
+// last document returned by the previous page.
+type pageCursor struct {
+	UpdatedAt time.Time          `bson:"updated_at"`
+	ID        primitive.ObjectID `bson:"_id"`
+}
+
+// encodePageToken packs a pageCursor into the opaque string handed back to
+// callers as NextPageToken.
+func encodePageToken(updatedAt time.Time, id primitive.ObjectID) (string, error) {
+	raw, err := bson.Marshal(pageCursor{UpdatedAt: updatedAt, ID: id})
+	if err != nil {
+		return "", fmt.Errorf("encode page token err: %w", err)
+	}
+	return base64.URLEncoding.EncodeToString(raw), nil
+}
+
+// decodePageToken reverses encodePageToken. Tokens are opaque to callers and
+// must only ever be round-tripped through a prior NextPageToken.
+func decodePageToken(token string) (*pageCursor, error) {
+	raw, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("decode page token err: %w", err)
+	}
+
+	var cursor pageCursor
+	if err := bson.Unmarshal(raw, &cursor); err != nil {
+		return nil, fmt.Errorf("decode page token err: %w", err)
+	}
+	return &cursor, nil
+}
+
 func (s *MongoImpl) EnsureIndex(ctx context.Context) error {
 	// use CreateMany here for future custom
 	_, err := s.memberCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
 		makeIndex(true, bson.D{{Key: "injective_address", Value: 1}}),
 		makeIndex(false, bson.D{{Key: "is_default_guild_member", Value: 1}}),
 		makeIndex(false, bson.D{{Key: "guild_id", Value: 1}}),
+		// keeps ListGuildMembers' cursor pagination index-covered
+		makeIndex(false, bson.D{
+			{Key: "guild_id", Value: 1},
+			{Key: "since", Value: -1},
+			{Key: "_id", Value: -1},
+		}),
 	})
 	if err != nil {
 		return err
@@ -90,6 +174,17 @@ func (s *MongoImpl) EnsureIndex(ctx context.Context) error {
 		makeIndex(false, bson.D{{Key: "injective_address", Value: 1}}),
 		makeIndex(false, bson.D{{Key: "guild_id", Value: 1}}),
 		makeIndex(false, bson.D{{Key: "updated_at", Value: -1}}),
+		// keeps ListAccountPortfolios' cursor pagination index-covered
+		makeIndex(false, bson.D{
+			{Key: "injective_address", Value: 1},
+			{Key: "updated_at", Value: -1},
+			{Key: "_id", Value: -1},
+		}),
+		// dedup key for AddAccountPortfolios' upsert-based bulk write
+		makeIndex(true, bson.D{
+			{Key: "injective_address", Value: 1},
+			{Key: "updated_at", Value: 1},
+		}),
 	})
 	if err != nil {
 		return err
@@ -98,6 +193,52 @@ func (s *MongoImpl) EnsureIndex(ctx context.Context) error {
 	_, err = s.guildPortfolioCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
 		makeIndex(false, bson.D{{Key: "guild_id", Value: 1}}),
 		makeIndex(false, bson.D{{Key: "updated_at", Value: -1}}),
+		// keeps ListGuildPortfolios' cursor pagination index-covered
+		makeIndex(false, bson.D{
+			{Key: "guild_id", Value: 1},
+			{Key: "updated_at", Value: -1},
+			{Key: "_id", Value: -1},
+		}),
+		// dedup key for AddGuildPortfolios' upsert-based bulk write
+		makeIndex(true, bson.D{
+			{Key: "guild_id", Value: 1},
+			{Key: "updated_at", Value: 1},
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	// RollupInterval is required alongside RawRetention: without it the
+	// compactor never starts (see NewService), so a TTL index here would
+	// delete raw snapshots with nothing rolling them up first.
+	if s.retentionPolicy.RawRetention > 0 && s.retentionPolicy.RollupInterval > 0 {
+		expireAfterSeconds := int32(s.retentionPolicy.RawRetention.Seconds())
+
+		_, err = s.accountPortfolioCollection.Indexes().CreateOne(ctx, makeTTLIndex("updated_at", expireAfterSeconds))
+		if err != nil {
+			return err
+		}
+
+		_, err = s.guildPortfolioCollection.Indexes().CreateOne(ctx, makeTTLIndex("updated_at", expireAfterSeconds))
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = s.accountPortfolioDailyCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		makeIndex(true, bson.D{{Key: "injective_address", Value: 1}, {Key: "day", Value: 1}}),
+		// keeps ListAccountPortfolios' cursor pagination index-covered when
+		// it falls back to this collection
+		makeIndex(false, bson.D{{Key: "injective_address", Value: 1}, {Key: "updated_at", Value: -1}, {Key: "_id", Value: -1}}),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.guildPortfolioDailyCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		makeIndex(true, bson.D{{Key: "guild_id", Value: 1}, {Key: "day", Value: 1}}),
+		makeIndex(false, bson.D{{Key: "guild_id", Value: 1}, {Key: "updated_at", Value: -1}, {Key: "_id", Value: -1}}),
 	})
 	if err != nil {
 		return err
@@ -109,10 +250,10 @@ func (s *MongoImpl) EnsureIndex(ctx context.Context) error {
 func (s *MongoImpl) ListGuildPortfolios(
 	ctx context.Context,
 	filter model.GuildPortfoliosFilter,
-) (result []*model.GuildPortfolio, err error) {
+) (result []*model.GuildPortfolio, nextPageToken string, err error) {
 	guildObjectID, err := primitive.ObjectIDFromHex(filter.GuildID)
 	if err != nil {
-		return nil, fmt.Errorf("cannot parse guildID: %w", err)
+		return nil, "", fmt.Errorf("cannot parse guildID: %w", err)
 	}
 
 	portfolioFilter := bson.M{
@@ -132,29 +273,59 @@ func (s *MongoImpl) ListGuildPortfolios(
 		portfolioFilter["updated_at"] = updatedAtFilter
 	}
 
+	if filter.PageToken != "" {
+		cursor, err := decodePageToken(filter.PageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		portfolioFilter["$or"] = []bson.M{
+			{"updated_at": bson.M{"$lt": cursor.UpdatedAt}},
+			{"updated_at": cursor.UpdatedAt, "_id": bson.M{"$lt": cursor.ID}},
+		}
+	}
+
 	opt := &options.FindOptions{}
-	opt.SetSort(bson.M{"updated_at": -1})
-	if filter.Limit != nil {
-		opt.SetLimit(*filter.Limit)
+	opt.SetSort(bson.D{{Key: "updated_at", Value: -1}, {Key: "_id", Value: -1}})
+	if filter.Limit != nil && *filter.Limit > 0 {
+		// fetch one extra document to know whether another page follows
+		opt.SetLimit(*filter.Limit + 1)
+	}
+
+	useDaily := s.usesDailyRollup(filter.EndTime)
+	coll := s.guildPortfolioCollection
+	if useDaily {
+		coll = s.guildPortfolioDailyCollection
 	}
 
-	cur, err := s.guildPortfolioCollection.Find(ctx, portfolioFilter, opt)
+	result, err = findGuildPortfolios(ctx, coll, portfolioFilter, opt)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	defer cur.Close(ctx)
 
-	for cur.Next(ctx) {
-		var guildPortfolio model.GuildPortfolio
-		err := cur.Decode(&guildPortfolio)
+	if !useDaily && s.straddlesRetentionWindow(filter.StartTime) {
+		// the range starts before the retention window but ends inside it:
+		// the older portion may only exist in the daily rollup now, so query
+		// it too and merge rather than silently returning a partial range.
+		dailyResult, err := findGuildPortfolios(ctx, s.guildPortfolioDailyCollection, portfolioFilter, opt)
 		if err != nil {
-			return nil, err
+			return nil, "", err
+		}
+		result = mergeGuildPortfoliosDesc(result, dailyResult)
+		if filter.Limit != nil && *filter.Limit > 0 && int64(len(result)) > *filter.Limit+1 {
+			result = result[:*filter.Limit+1]
 		}
+	}
 
-		result = append(result, &guildPortfolio)
+	if filter.Limit != nil && *filter.Limit > 0 && int64(len(result)) > *filter.Limit {
+		last := result[*filter.Limit-1]
+		nextPageToken, err = encodePageToken(last.UpdatedAt, last.ID)
+		if err != nil {
+			return nil, "", err
+		}
+		result = result[:*filter.Limit]
 	}
 
-	return result, nil
+	return result, nextPageToken, nil
 }
 
 func (s *MongoImpl) AddGuild(ctx context.Context, guild *model.Guild) (*primitive.ObjectID, error) {
@@ -250,26 +421,32 @@ func (s *MongoImpl) GetSingleGuild(ctx context.Context, guildID string) (*model.
 	return &guild, nil
 }
 
-func (s *MongoImpl) AddGuildPortfolios(ctx context.Context, portfolios []*model.GuildPortfolio) error {
-	docs := make([]interface{}, len(portfolios))
+// AddGuildPortfolios upserts portfolios, keyed by (guild_id, updated_at), via
+// an unordered bulk write so a retried snapshot is deduplicated in place and
+// one bad document doesn't fail the rest of the batch.
+func (s *MongoImpl) AddGuildPortfolios(ctx context.Context, portfolios []*model.GuildPortfolio) (*db.BulkResult, error) {
+	models := make([]mongo.WriteModel, len(portfolios))
 	for i, p := range portfolios {
-		docs[i] = p
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"guild_id": p.GuildID, "updated_at": p.UpdatedAt}).
+			SetUpdate(bson.M{"$set": p}).
+			SetUpsert(true)
 	}
 
-	_, err := s.guildPortfolioCollection.InsertMany(ctx, docs)
-	return err
+	res, err := s.guildPortfolioCollection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return toBulkResult(res, err)
 }
 
 func (s *MongoImpl) ListGuildMembers(
 	ctx context.Context,
 	memberFilter model.MemberFilter,
-) (result []*model.GuildMember, err error) {
+) (result []*model.GuildMember, nextPageToken string, err error) {
 	filter := bson.M{}
 
 	if memberFilter.GuildID != nil {
 		guildObjectID, err := primitive.ObjectIDFromHex(*memberFilter.GuildID)
 		if err != nil {
-			return nil, fmt.Errorf("cannot parse guildID: %w", err)
+			return nil, "", fmt.Errorf("cannot parse guildID: %w", err)
 		}
 		filter["guild_id"] = guildObjectID
 	}
@@ -282,9 +459,27 @@ func (s *MongoImpl) ListGuildMembers(
 		filter["injective_address"] = *memberFilter.InjectiveAddress
 	}
 
-	cur, err := s.memberCollection.Find(ctx, filter)
+	if memberFilter.PageToken != "" {
+		cursor, err := decodePageToken(memberFilter.PageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		filter["$or"] = []bson.M{
+			{"since": bson.M{"$lt": cursor.UpdatedAt}},
+			{"since": cursor.UpdatedAt, "_id": bson.M{"$lt": cursor.ID}},
+		}
+	}
+
+	opts := &options.FindOptions{}
+	opts.SetSort(bson.D{{Key: "since", Value: -1}, {Key: "_id", Value: -1}})
+	if memberFilter.Limit != nil && *memberFilter.Limit > 0 {
+		// fetch one extra document to know whether another page follows
+		opts.SetLimit(*memberFilter.Limit + 1)
+	}
+
+	cur, err := s.memberCollection.Find(ctx, filter, opts)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer cur.Close(ctx)
 
@@ -292,13 +487,22 @@ func (s *MongoImpl) ListGuildMembers(
 		var member model.GuildMember
 		err := cur.Decode(&member)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 
 		result = append(result, &member)
 	}
 
-	return result, nil
+	if memberFilter.Limit != nil && *memberFilter.Limit > 0 && int64(len(result)) > *memberFilter.Limit {
+		last := result[*memberFilter.Limit-1]
+		nextPageToken, err = encodePageToken(last.Since, last.ID)
+		if err != nil {
+			return nil, "", err
+		}
+		result = result[:*memberFilter.Limit]
+	}
+
+	return result, nextPageToken, nil
 }
 
 func (s *MongoImpl) upsertMember(
@@ -382,7 +586,7 @@ func (s *MongoImpl) AddMember(ctx context.Context, guildID string, address model
 			}
 
 			if guild.MemberCount >= guild.Capacity {
-				return nil, ErrMemberExceedCap
+				return nil, db.ErrMemberExceedCap
 			}
 
 			_, err = s.adjustMemberCount(sessCtx, guildObjectID, 1)
@@ -398,7 +602,7 @@ func (s *MongoImpl) AddMember(ctx context.Context, guildID string, address model
 
 		// duplicate member, revert transaction
 		if upsertRes.UpsertedCount < 1 {
-			return nil, ErrAlreadyMember
+			return nil, db.ErrAlreadyMember
 		}
 
 		return nil, nil
@@ -466,7 +670,7 @@ func (s *MongoImpl) GetAccountPortfolio(ctx context.Context, address model.Addre
 func (s *MongoImpl) ListAccountPortfolios(
 	ctx context.Context,
 	filter model.AccountPortfoliosFilter,
-) (result []*model.AccountPortfolio, err error) {
+) (result []*model.AccountPortfolio, nextPageToken string, err error) {
 	portfolioFilter := bson.M{
 		"injective_address": filter.InjectiveAddress.String(),
 	}
@@ -484,45 +688,479 @@ func (s *MongoImpl) ListAccountPortfolios(
 		portfolioFilter["updated_at"] = updatedAtFilter
 	}
 
+	if filter.PageToken != "" {
+		cursor, err := decodePageToken(filter.PageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		portfolioFilter["$or"] = []bson.M{
+			{"updated_at": bson.M{"$lt": cursor.UpdatedAt}},
+			{"updated_at": cursor.UpdatedAt, "_id": bson.M{"$lt": cursor.ID}},
+		}
+	}
+
 	opts := &options.FindOptions{}
-	opts.SetSort(bson.M{"updated_at": -1})
+	opts.SetSort(bson.D{{Key: "updated_at", Value: -1}, {Key: "_id", Value: -1}})
+	if filter.Limit != nil && *filter.Limit > 0 {
+		// fetch one extra document to know whether another page follows
+		opts.SetLimit(*filter.Limit + 1)
+	}
 
-	cur, err := s.accountPortfolioCollection.Find(ctx, portfolioFilter, opts)
+	useDaily := s.usesDailyRollup(filter.EndTime)
+	coll := s.accountPortfolioCollection
+	if useDaily {
+		coll = s.accountPortfolioDailyCollection
+	}
+
+	result, err = findAccountPortfolios(ctx, coll, portfolioFilter, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !useDaily && s.straddlesRetentionWindow(filter.StartTime) {
+		// the range starts before the retention window but ends inside it:
+		// the older portion may only exist in the daily rollup now, so query
+		// it too and merge rather than silently returning a partial range.
+		dailyResult, err := findAccountPortfolios(ctx, s.accountPortfolioDailyCollection, portfolioFilter, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		result = mergeAccountPortfoliosDesc(result, dailyResult)
+		if filter.Limit != nil && *filter.Limit > 0 && int64(len(result)) > *filter.Limit+1 {
+			result = result[:*filter.Limit+1]
+		}
+	}
+
+	if filter.Limit != nil && *filter.Limit > 0 && int64(len(result)) > *filter.Limit {
+		last := result[*filter.Limit-1]
+		nextPageToken, err = encodePageToken(last.UpdatedAt, last.ID)
+		if err != nil {
+			return nil, "", err
+		}
+		result = result[:*filter.Limit]
+	}
+
+	return result, nextPageToken, nil
+}
+
+// dateTruncUnit picks the coarsest standard $dateTrunc unit and bin size
+// that represents bucket, so callers can pass an arbitrary duration (e.g.
+// 15 minutes, 4 hours, 90 minutes) instead of being limited to Mongo's fixed
+// units. The finest unit used is minute: any sub-minute remainder of bucket
+// is dropped.
+func dateTruncUnit(bucket time.Duration) (unit string, binSize int32) {
+	switch {
+	case bucket%(24*time.Hour) == 0:
+		return "day", int32(bucket / (24 * time.Hour))
+	case bucket%time.Hour == 0:
+		return "hour", int32(bucket / time.Hour)
+	default:
+		return "minute", int32(bucket / time.Minute)
+	}
+}
+
+// GetGuildLeaderboard ranks a guild's members by the change in their denom
+// balance between startTime and endTime, returning at most topN entries,
+// highest change first.
+func (s *MongoImpl) GetGuildLeaderboard(
+	ctx context.Context,
+	guildID string,
+	denom string,
+	startTime, endTime time.Time,
+	topN int64,
+) ([]*model.LeaderboardEntry, error) {
+	// "at most topN entries" makes topN = 0 a valid request for no rows; $limit
+	// itself errors on a non-positive value, and pgimpl's LIMIT already treats
+	// it as empty, so return early here for parity.
+	if topN <= 0 {
+		return nil, nil
+	}
+
+	guildObjectID, err := primitive.ObjectIDFromHex(guildID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse guildID: %w", err)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"guild_id":   guildObjectID,
+			"updated_at": bson.M{"$gte": startTime, "$lt": endTime},
+		}}},
+		{{Key: "$unwind", Value: "$balances"}},
+		{{Key: "$match", Value: bson.M{"balances.denom": denom}}},
+		{{Key: "$sort", Value: bson.D{{Key: "injective_address", Value: 1}, {Key: "updated_at", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         "$injective_address",
+			"start_value": bson.M{"$first": bson.M{"$toDecimal": "$balances.amount"}},
+			"end_value":   bson.M{"$last": bson.M{"$toDecimal": "$balances.amount"}},
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"change": bson.M{"$subtract": bson.A{"$end_value", "$start_value"}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "change", Value: -1}}}},
+		{{Key: "$limit", Value: topN}},
+	}
+
+	cur, err := s.accountPortfolioCollection.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, err
 	}
 	defer cur.Close(ctx)
 
+	var result []*model.LeaderboardEntry
 	for cur.Next(ctx) {
-		var portfolio model.AccountPortfolio
-		err := cur.Decode(&portfolio)
-		if err != nil {
+		var entry model.LeaderboardEntry
+		if err := cur.Decode(&entry); err != nil {
 			return nil, err
 		}
+		result = append(result, &entry)
+	}
 
-		result = append(result, &portfolio)
+	return result, nil
+}
+
+// GetGuildPortfolioSeries downsamples a guild's portfolio snapshots between
+// startTime and endTime into OHLC-style buckets of the given width, computed
+// server-side via aggregation so clients never pull raw snapshots for charts.
+func (s *MongoImpl) GetGuildPortfolioSeries(
+	ctx context.Context,
+	guildID string,
+	bucket time.Duration,
+	startTime, endTime time.Time,
+) ([]*model.PortfolioBucket, error) {
+	guildObjectID, err := primitive.ObjectIDFromHex(guildID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse guildID: %w", err)
 	}
+
+	unit, binSize := dateTruncUnit(bucket)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"guild_id":   guildObjectID,
+			"updated_at": bson.M{"$gte": startTime, "$lt": endTime},
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"bucket_start": bson.M{"$dateTrunc": bson.M{
+				"date":    "$updated_at",
+				"unit":    unit,
+				"binSize": binSize,
+			}},
+			"value": bson.M{"$toDecimal": "$total_value"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "updated_at", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$bucket_start",
+			"open":  bson.M{"$first": "$value"},
+			"high":  bson.M{"$max": "$value"},
+			"low":   bson.M{"$min": "$value"},
+			"close": bson.M{"$last": "$value"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+
+	cur, err := s.guildPortfolioCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var result []*model.PortfolioBucket
+	for cur.Next(ctx) {
+		var b model.PortfolioBucket
+		if err := cur.Decode(&b); err != nil {
+			return nil, err
+		}
+		result = append(result, &b)
+	}
+
 	return result, nil
 }
 
-// AddAccountPortfolios add portfolio snapshots in single write call
+// rollupCutoff is the point past which raw snapshots are at risk of TTL
+// expiry before the next compactor run, and so must be rolled up now.
+func (s *MongoImpl) rollupCutoff() time.Time {
+	return time.Now().Add(-(s.retentionPolicy.RawRetention - s.retentionPolicy.RollupInterval))
+}
+
+// usesDailyRollup reports whether a query's end time predates the raw
+// retention window, meaning the raw collection may have already TTL-expired
+// the relevant documents and the `*_daily` rollup should be queried instead.
+func (s *MongoImpl) usesDailyRollup(endTime *time.Time) bool {
+	if s.retentionPolicy.RawRetention <= 0 || s.retentionPolicy.RollupInterval <= 0 || endTime == nil {
+		return false
+	}
+	return endTime.Before(time.Now().Add(-s.retentionPolicy.RawRetention))
+}
+
+// straddlesRetentionWindow reports whether a query's start time predates the
+// raw retention window while its end time does not (usesDailyRollup is
+// false), meaning the requested range spans both the raw collection and data
+// that has already been rolled up into `*_daily`. Callers in that case must
+// query both collections and merge, or the portion of the range older than
+// the retention window is silently dropped.
+func (s *MongoImpl) straddlesRetentionWindow(startTime *time.Time) bool {
+	if s.retentionPolicy.RawRetention <= 0 || s.retentionPolicy.RollupInterval <= 0 || startTime == nil {
+		return false
+	}
+	return startTime.Before(time.Now().Add(-s.retentionPolicy.RawRetention))
+}
+
+// findGuildPortfolios runs filter/opt against coll and decodes every match.
+func findGuildPortfolios(
+	ctx context.Context,
+	coll *mongo.Collection,
+	filter bson.M,
+	opt *options.FindOptions,
+) ([]*model.GuildPortfolio, error) {
+	cur, err := coll.Find(ctx, filter, opt)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var result []*model.GuildPortfolio
+	for cur.Next(ctx) {
+		var guildPortfolio model.GuildPortfolio
+		if err := cur.Decode(&guildPortfolio); err != nil {
+			return nil, err
+		}
+		result = append(result, &guildPortfolio)
+	}
+	return result, cur.Err()
+}
+
+// mergeGuildPortfoliosDesc merges two slices already sorted descending by
+// (updated_at, _id) into one slice in the same order, used to combine raw and
+// daily-rollup results for a range that straddles the retention window.
+func mergeGuildPortfoliosDesc(a, b []*model.GuildPortfolio) []*model.GuildPortfolio {
+	merged := make([]*model.GuildPortfolio, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if guildPortfolioBefore(a[i], b[j]) {
+			merged = append(merged, a[i])
+			i++
+		} else {
+			merged = append(merged, b[j])
+			j++
+		}
+	}
+	merged = append(merged, a[i:]...)
+	merged = append(merged, b[j:]...)
+	return merged
+}
+
+// guildPortfolioBefore reports whether x sorts before y in (updated_at DESC,
+// _id DESC) order.
+func guildPortfolioBefore(x, y *model.GuildPortfolio) bool {
+	if !x.UpdatedAt.Equal(y.UpdatedAt) {
+		return x.UpdatedAt.After(y.UpdatedAt)
+	}
+	return x.ID.Hex() > y.ID.Hex()
+}
+
+// findAccountPortfolios runs filter/opt against coll and decodes every match.
+func findAccountPortfolios(
+	ctx context.Context,
+	coll *mongo.Collection,
+	filter bson.M,
+	opt *options.FindOptions,
+) ([]*model.AccountPortfolio, error) {
+	cur, err := coll.Find(ctx, filter, opt)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var result []*model.AccountPortfolio
+	for cur.Next(ctx) {
+		var portfolio model.AccountPortfolio
+		if err := cur.Decode(&portfolio); err != nil {
+			return nil, err
+		}
+		result = append(result, &portfolio)
+	}
+	return result, cur.Err()
+}
+
+// mergeAccountPortfoliosDesc merges two slices already sorted descending by
+// (updated_at, _id) into one slice in the same order, used to combine raw and
+// daily-rollup results for a range that straddles the retention window.
+func mergeAccountPortfoliosDesc(a, b []*model.AccountPortfolio) []*model.AccountPortfolio {
+	merged := make([]*model.AccountPortfolio, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if accountPortfolioBefore(a[i], b[j]) {
+			merged = append(merged, a[i])
+			i++
+		} else {
+			merged = append(merged, b[j])
+			j++
+		}
+	}
+	merged = append(merged, a[i:]...)
+	merged = append(merged, b[j:]...)
+	return merged
+}
+
+// accountPortfolioBefore reports whether x sorts before y in (updated_at
+// DESC, _id DESC) order.
+func accountPortfolioBefore(x, y *model.AccountPortfolio) bool {
+	if !x.UpdatedAt.Equal(y.UpdatedAt) {
+		return x.UpdatedAt.After(y.UpdatedAt)
+	}
+	return x.ID.Hex() > y.ID.Hex()
+}
+
+// rollupAccountPortfolios groups raw account_portfolios snapshots older than
+// rollupCutoff by day and address, keeping the last snapshot per bucket, and
+// merges the result into account_portfolios_daily.
+func (s *MongoImpl) rollupAccountPortfolios(ctx context.Context) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"updated_at": bson.M{"$lt": s.rollupCutoff()}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "updated_at", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         bson.M{"day": bson.M{"$dateTrunc": bson.M{"date": "$updated_at", "unit": "day"}}, "injective_address": "$injective_address"},
+			"guild_id":    bson.M{"$last": "$guild_id"},
+			"updated_at":  bson.M{"$last": "$updated_at"},
+			"total_value": bson.M{"$last": "$total_value"},
+			"balances":    bson.M{"$last": "$balances"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":               0,
+			"day":               "$_id.day",
+			"injective_address": "$_id.injective_address",
+			"guild_id":          1,
+			"updated_at":        1,
+			"total_value":       1,
+			"balances":          1,
+		}}},
+		{{Key: "$merge", Value: bson.M{
+			"into":        AccountPortfolioDailyCollectionName,
+			"on":          []string{"injective_address", "day"},
+			"whenMatched": "replace",
+		}}},
+	}
+
+	cur, err := s.accountPortfolioCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cur.Close(ctx)
+}
+
+// rollupGuildPortfolios groups raw guild_portfolios snapshots older than
+// rollupCutoff by day and guild, keeping the last snapshot per bucket, and
+// merges the result into guild_portfolios_daily.
+func (s *MongoImpl) rollupGuildPortfolios(ctx context.Context) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"updated_at": bson.M{"$lt": s.rollupCutoff()}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "updated_at", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         bson.M{"day": bson.M{"$dateTrunc": bson.M{"date": "$updated_at", "unit": "day"}}, "guild_id": "$guild_id"},
+			"updated_at":  bson.M{"$last": "$updated_at"},
+			"total_value": bson.M{"$last": "$total_value"},
+			"balances":    bson.M{"$last": "$balances"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":         0,
+			"day":         "$_id.day",
+			"guild_id":    "$_id.guild_id",
+			"updated_at":  1,
+			"total_value": 1,
+			"balances":    1,
+		}}},
+		{{Key: "$merge", Value: bson.M{
+			"into":        GuildPortfolioDailyCollectionName,
+			"on":          []string{"guild_id", "day"},
+			"whenMatched": "replace",
+		}}},
+	}
+
+	cur, err := s.guildPortfolioCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cur.Close(ctx)
+}
+
+// RunRetentionRollup rolls up raw snapshots that are about to fall out of the
+// retention window into the daily collections. It is safe to call
+// concurrently with itself and with ongoing writes/reads.
+func (s *MongoImpl) RunRetentionRollup(ctx context.Context) error {
+	if s.retentionPolicy.RawRetention <= 0 {
+		return nil
+	}
+
+	if err := s.rollupAccountPortfolios(ctx); err != nil {
+		return fmt.Errorf("rollup account portfolios err: %w", err)
+	}
+
+	if err := s.rollupGuildPortfolios(ctx); err != nil {
+		return fmt.Errorf("rollup guild portfolios err: %w", err)
+	}
+
+	return nil
+}
+
+// StartRetentionCompactor runs RunRetentionRollup on RetentionPolicy.RollupInterval
+// until ctx is cancelled. Errors from individual runs are sent on the
+// returned channel on a best-effort basis; the compactor keeps retrying on
+// the next tick regardless.
+func (s *MongoImpl) StartRetentionCompactor(ctx context.Context) <-chan error {
+	errCh := make(chan error, 1)
+	if s.retentionPolicy.RawRetention <= 0 || s.retentionPolicy.RollupInterval <= 0 {
+		close(errCh)
+		return errCh
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.retentionPolicy.RollupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.RunRetentionRollup(ctx); err != nil {
+					select {
+					case errCh <- err:
+					default:
+					}
+				}
+			}
+		}
+	}()
+
+	return errCh
+}
+
+// AddAccountPortfolios upserts portfolios, keyed by (injective_address,
+// updated_at); see AddGuildPortfolios.
 func (s *MongoImpl) AddAccountPortfolios(
 	ctx context.Context,
 	portfolios []*model.AccountPortfolio,
-) error {
-	docs := make([]interface{}, len(portfolios))
+) (*db.BulkResult, error) {
+	models := make([]mongo.WriteModel, len(portfolios))
 	for i, p := range portfolios {
-		docs[i] = p
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"injective_address": p.InjectiveAddress, "updated_at": p.UpdatedAt}).
+			SetUpdate(bson.M{"$set": p}).
+			SetUpsert(true)
 	}
 
-	_, err := s.accountPortfolioCollection.InsertMany(ctx, docs)
-	return err
+	res, err := s.accountPortfolioCollection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return toBulkResult(res, err)
 }
 
 func (s *MongoImpl) Disconnect(ctx context.Context) error {
+	if s.stopCompactor != nil {
+		s.stopCompactor()
+	}
 	return s.client.Disconnect(ctx)
 }
 
 func (s *MongoImpl) GetClient() *mongo.Client {
 	return s.client
-}
\ No newline at end of file
+}
@@ -0,0 +1,32 @@
+//go:build integration
+
+package mongoimpl_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/InjectiveLabs/injective-guilds-service/internal/db/dbtest"
+	"github.com/InjectiveLabs/injective-guilds-service/internal/db/mongoimpl"
+)
+
+// TestDBServiceSuite runs the shared db.DBService behavioral suite against a
+// real MongoDB replica set pointed to by MONGO_TEST_URI (AddMember,
+// RemoveMember and DeleteGuild use multi-document transactions, which
+// require one). Run with: go test -tags integration ./internal/db/mongoimpl/...
+func TestDBServiceSuite(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set, skipping mongoimpl integration suite")
+	}
+
+	ctx := context.Background()
+	svc, err := mongoimpl.NewService(ctx, uri, "guilds_test", mongoimpl.RetentionPolicy{})
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	defer svc.Disconnect(ctx)
+
+	dbtest.RunSuite(t, svc)
+}
@@ -0,0 +1,134 @@
+package pgimpl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/InjectiveLabs/injective-guilds-service/internal/db"
+)
+
+// eventBufferSize bounds how many events Subscribe will buffer for a slow
+// consumer before it starts blocking the LISTEN connection.
+const eventBufferSize = 64
+
+// notifyChannel is the Postgres NOTIFY channel backing a single EventKind,
+// populated by the triggers in migrations/0002_change_notify.sql.
+func notifyChannel(kind db.EventKind) (string, bool) {
+	switch kind {
+	case db.EventKindGuild:
+		return "guild_changes", true
+	case db.EventKindMember:
+		return "member_changes", true
+	case db.EventKindGuildPortfolio:
+		return "guild_portfolio_changes", true
+	default:
+		return "", false
+	}
+}
+
+// notifyPayload mirrors the JSON object built by notify_row_change() and
+// notify_row_change_ref() in migrations/0002_change_notify.sql. For
+// EventKindGuildPortfolio, Row only carries the row's identity columns
+// (id, guild_id, updated_at) rather than the full row, since balances is
+// unbounded and would risk overflowing Postgres' NOTIFY payload cap.
+type notifyPayload struct {
+	Operation string          `json:"operation"`
+	Row       json.RawMessage `json:"row"`
+}
+
+// Subscribe listens on the Postgres NOTIFY channels backing kinds and emits
+// a db.Event per insert/update/delete performed via the triggers installed
+// by migrations/0002_change_notify.sql. The returned channel is closed once
+// ctx is cancelled.
+//
+// Unlike the Mongo backend's resumable change streams, LISTEN/NOTIFY has no
+// durable log to replay: this is at-most-once delivery. Any mutation that
+// commits while there is no active LISTEN connection for its channel --
+// before a Subscribe call has issued its LISTEN, or across a dropped
+// connection and re-subscribe -- is not redelivered. Notifications that
+// arrive after Subscribe returns are queued on the connection until read.
+// Callers that need to not miss events across a restart must reconcile with
+// a direct read (e.g. List*) after (re)subscribing.
+func (s *PgImpl) Subscribe(ctx context.Context, kinds []db.EventKind) (<-chan db.Event, error) {
+	channels := make(map[string]db.EventKind, len(kinds))
+	for _, kind := range kinds {
+		channel, ok := notifyChannel(kind)
+		if !ok {
+			continue
+		}
+		channels[channel] = kind
+	}
+	if len(channels) == 0 {
+		return nil, fmt.Errorf("subscribe: no notify channel for kinds %v", kinds)
+	}
+
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquire listen connection err: %w", err)
+	}
+
+	for channel := range channels {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgIdentQuote(channel)); err != nil {
+			conn.Release()
+			return nil, fmt.Errorf("listen %s err: %w", channel, err)
+		}
+	}
+
+	out := make(chan db.Event, eventBufferSize)
+
+	go func() {
+		defer conn.Release()
+		defer close(out)
+
+		for {
+			notification, err := conn.Conn().WaitForNotification(ctx)
+			if err != nil {
+				return
+			}
+
+			kind, ok := channels[notification.Channel]
+			if !ok {
+				continue
+			}
+
+			var payload notifyPayload
+			if err := json.Unmarshal([]byte(notification.Payload), &payload); err != nil {
+				continue
+			}
+
+			eventType, ok := operationToEventType(payload.Operation)
+			if !ok {
+				continue
+			}
+
+			event := db.Event{Kind: kind, Type: eventType, Data: payload.Row}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func operationToEventType(op string) (db.EventType, bool) {
+	switch op {
+	case "insert":
+		return db.EventTypeInsert, true
+	case "update":
+		return db.EventTypeUpdate, true
+	case "delete":
+		return db.EventTypeDelete, true
+	default:
+		return "", false
+	}
+}
+
+// pgIdentQuote double-quotes channel, which is always one of the fixed,
+// package-defined strings returned by notifyChannel -- never user input.
+func pgIdentQuote(channel string) string {
+	return `"` + channel + `"`
+}
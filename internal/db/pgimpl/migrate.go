@@ -0,0 +1,81 @@
+package pgimpl
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrate applies any migration files not yet recorded in schema_migrations,
+// in filename order, each inside its own transaction.
+func migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	if _, err := pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			name       TEXT PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`); err != nil {
+		return fmt.Errorf("create schema_migrations err: %w", err)
+	}
+
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return fmt.Errorf("read migrations err: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := applyMigration(ctx, pool, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func applyMigration(ctx context.Context, pool *pgxpool.Pool, name string) error {
+	var applied bool
+	row := pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE name = $1)`, name)
+	if err := row.Scan(&applied); err != nil {
+		return fmt.Errorf("check migration %s err: %w", name, err)
+	}
+	if applied {
+		return nil
+	}
+
+	contents, err := migrationFiles.ReadFile("migrations/" + name)
+	if err != nil {
+		return fmt.Errorf("read migration %s err: %w", name, err)
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin migration %s err: %w", name, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, string(contents)); err != nil {
+		return fmt.Errorf("apply migration %s err: %w", name, err)
+	}
+
+	if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (name) VALUES ($1)`, name); err != nil {
+		return fmt.Errorf("record migration %s err: %w", name, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit migration %s err: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package pgimpl
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// pageCursor is the decoded form of an opaque PageToken: the sort key and id
+// of the last row returned by the previous page, used to build a keyset
+// `(sort_key, id) < (?, ?)` predicate for the next one.
+type pageCursor struct {
+	SortKey time.Time `json:"sort_key"`
+	ID      string    `json:"id"`
+}
+
+// encodePageToken packs a pageCursor into the opaque string handed back to
+// callers as NextPageToken.
+func encodePageToken(sortKey time.Time, id string) (string, error) {
+	raw, err := json.Marshal(pageCursor{SortKey: sortKey, ID: id})
+	if err != nil {
+		return "", fmt.Errorf("encode page token err: %w", err)
+	}
+	return base64.URLEncoding.EncodeToString(raw), nil
+}
+
+// decodePageToken reverses encodePageToken. Tokens are opaque to callers and
+// must only ever be round-tripped through a prior NextPageToken.
+func decodePageToken(token string) (*pageCursor, error) {
+	raw, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("decode page token err: %w", err)
+	}
+
+	var cursor pageCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, fmt.Errorf("decode page token err: %w", err)
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,747 @@
+// Package pgimpl implements db.DBService on top of PostgreSQL via pgx,
+// using relational tables for guilds/members and JSONB columns for
+// portfolio balances. It is a drop-in alternative to mongoimpl for
+// operators who don't want to run MongoDB.
+package pgimpl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/InjectiveLabs/injective-guilds-service/internal/db"
+	"github.com/InjectiveLabs/injective-guilds-service/internal/db/model"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const connectionTimeout = 30 * time.Second
+
+type PgImpl struct {
+	pool *pgxpool.Pool
+}
+
+var _ db.DBService = (*PgImpl)(nil)
+
+// NewService connects to connectionURL, runs any pending schema migrations,
+// and returns a ready-to-use db.DBService backed by PostgreSQL.
+func NewService(ctx context.Context, connectionURL string) (db.DBService, error) {
+	ctx, cancel := context.WithTimeout(ctx, connectionTimeout)
+	defer cancel()
+
+	pool, err := pgxpool.Connect(ctx, connectionURL)
+	if err != nil {
+		return nil, fmt.Errorf("connect postgres err: %w", err)
+	}
+
+	if err := migrate(ctx, pool); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return &PgImpl{pool: pool}, nil
+}
+
+// EnsureIndex (re-)applies schema migrations. It is idempotent and safe to
+// call on every startup, matching mongoimpl's EnsureIndex semantics.
+func (s *PgImpl) EnsureIndex(ctx context.Context) error {
+	return migrate(ctx, s.pool)
+}
+
+func newObjectIDHex() string {
+	return primitive.NewObjectID().Hex()
+}
+
+func marshalBalances(balances []model.Balance) ([]byte, error) {
+	if balances == nil {
+		balances = []model.Balance{}
+	}
+	return json.Marshal(balances)
+}
+
+func unmarshalBalances(raw []byte) ([]model.Balance, error) {
+	var balances []model.Balance
+	if err := json.Unmarshal(raw, &balances); err != nil {
+		return nil, fmt.Errorf("unmarshal balances err: %w", err)
+	}
+	return balances, nil
+}
+
+func (s *PgImpl) AddGuild(ctx context.Context, guild *model.Guild) (*primitive.ObjectID, error) {
+	id := primitive.NewObjectID()
+
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO guilds (id, name, description, member_count, capacity)
+		VALUES ($1, $2, $3, $4, $5)
+	`, id.Hex(), guild.Name, guild.Description, guild.MemberCount, guild.Capacity)
+	if err != nil {
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+func (s *PgImpl) DeleteGuild(ctx context.Context, guildID string) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM guilds WHERE id = $1`, guildID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM members WHERE guild_id = $1`, guildID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM account_portfolios WHERE guild_id = $1`, guildID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM guild_portfolios WHERE guild_id = $1`, guildID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *PgImpl) ListAllGuilds(ctx context.Context) ([]*model.Guild, error) {
+	rows, err := s.pool.Query(ctx, `SELECT id, name, description, member_count, capacity FROM guilds`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*model.Guild
+	for rows.Next() {
+		guild, err := scanGuild(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, guild)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *PgImpl) GetSingleGuild(ctx context.Context, guildID string) (*model.Guild, error) {
+	row := s.pool.QueryRow(ctx, `
+		SELECT id, name, description, member_count, capacity FROM guilds WHERE id = $1
+	`, guildID)
+
+	guild, err := scanGuild(row)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, db.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return guild, nil
+}
+
+// rowScanner is satisfied by both pgx.Row and pgx.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanGuild(row rowScanner) (*model.Guild, error) {
+	var (
+		idHex string
+		guild model.Guild
+	)
+
+	if err := row.Scan(&idHex, &guild.Name, &guild.Description, &guild.MemberCount, &guild.Capacity); err != nil {
+		return nil, err
+	}
+
+	objID, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse guild id: %w", err)
+	}
+	guild.Id = objID
+
+	return &guild, nil
+}
+
+func (s *PgImpl) AddMember(ctx context.Context, guildID string, address model.Address, isDefaultMember bool) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if !isDefaultMember {
+		var memberCount, capacity int
+		row := tx.QueryRow(ctx, `SELECT member_count, capacity FROM guilds WHERE id = $1`, guildID)
+		if err := row.Scan(&memberCount, &capacity); err != nil {
+			return err
+		}
+
+		if memberCount >= capacity {
+			return db.ErrMemberExceedCap
+		}
+
+		if _, err := tx.Exec(ctx, `UPDATE guilds SET member_count = member_count + 1 WHERE id = $1`, guildID); err != nil {
+			return err
+		}
+	}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO members (id, guild_id, injective_address, is_default_guild_member, since)
+		VALUES ($1, $2, $3, $4, now())
+		ON CONFLICT (injective_address) DO NOTHING
+	`, newObjectIDHex(), guildID, address.String(), isDefaultMember)
+	if err != nil {
+		return err
+	}
+
+	// duplicate member, revert transaction
+	if tag.RowsAffected() < 1 {
+		return db.ErrAlreadyMember
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *PgImpl) RemoveMember(ctx context.Context, guildID string, address model.Address) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		DELETE FROM members WHERE guild_id = $1 AND injective_address = $2
+	`, guildID, address.String())
+	if err != nil {
+		return err
+	}
+
+	// expected to have 1 account deleted
+	if tag.RowsAffected() != 1 {
+		return fmt.Errorf("cannot delete")
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE guilds SET member_count = member_count - 1 WHERE id = $1`, guildID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM account_portfolios WHERE guild_id = $1 AND injective_address = $2
+	`, guildID, address.String()); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *PgImpl) ListGuildMembers(
+	ctx context.Context,
+	filter model.MemberFilter,
+) (result []*model.GuildMember, nextPageToken string, err error) {
+	where := []string{"1=1"}
+	args := []interface{}{}
+
+	if filter.GuildID != nil {
+		args = append(args, *filter.GuildID)
+		where = append(where, fmt.Sprintf("guild_id = $%d", len(args)))
+	}
+
+	if filter.IsDefaultMember != nil {
+		args = append(args, *filter.IsDefaultMember)
+		where = append(where, fmt.Sprintf("is_default_guild_member = $%d", len(args)))
+	}
+
+	if filter.InjectiveAddress != nil {
+		args = append(args, *filter.InjectiveAddress)
+		where = append(where, fmt.Sprintf("injective_address = $%d", len(args)))
+	}
+
+	if filter.PageToken != "" {
+		cursor, err := decodePageToken(filter.PageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, cursor.SortKey, cursor.ID)
+		where = append(where, fmt.Sprintf("(since, id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	limitClause := ""
+	if filter.Limit != nil && *filter.Limit > 0 {
+		args = append(args, *filter.Limit+1)
+		limitClause = fmt.Sprintf("LIMIT $%d", len(args))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, guild_id, injective_address, is_default_guild_member, since
+		FROM members
+		WHERE %s
+		ORDER BY since DESC, id DESC
+		%s
+	`, joinAnd(where), limitClause)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			idHex, guildIDHex string
+			member            model.GuildMember
+		)
+		if err := rows.Scan(&idHex, &guildIDHex, &member.InjectiveAddress, &member.IsDefaultGuildMember, &member.Since); err != nil {
+			return nil, "", err
+		}
+
+		if member.ID, err = primitive.ObjectIDFromHex(idHex); err != nil {
+			return nil, "", fmt.Errorf("cannot parse member id: %w", err)
+		}
+		if member.GuildID, err = primitive.ObjectIDFromHex(guildIDHex); err != nil {
+			return nil, "", fmt.Errorf("cannot parse guild id: %w", err)
+		}
+
+		result = append(result, &member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if filter.Limit != nil && *filter.Limit > 0 && int64(len(result)) > *filter.Limit {
+		last := result[*filter.Limit-1]
+		nextPageToken, err = encodePageToken(last.Since, last.ID.Hex())
+		if err != nil {
+			return nil, "", err
+		}
+		result = result[:*filter.Limit]
+	}
+
+	return result, nextPageToken, nil
+}
+
+// upsertWithSavepoints runs n upserts inside a single transaction, each
+// wrapped in its own SAVEPOINT, and returns a db.BulkResult the same way the
+// Mongo backend's unordered BulkWrite does: one bad row rolls back to its
+// savepoint and is recorded as a BulkWriteError, while every other row's
+// write still commits. Sending the same upserts as a pgx.Batch would not do
+// this -- SendBatch pipelines every queued command inside one implicit
+// transaction, so a single failure aborts everything queued after it.
+func (s *PgImpl) upsertWithSavepoints(
+	ctx context.Context,
+	n int,
+	exec func(tx pgx.Tx, i int) (inserted bool, err error),
+) (*db.BulkResult, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	result := &db.BulkResult{}
+	for i := 0; i < n; i++ {
+		savepoint := fmt.Sprintf("sp_%d", i)
+		if _, err := tx.Exec(ctx, "SAVEPOINT "+savepoint); err != nil {
+			return nil, err
+		}
+
+		inserted, err := exec(tx, i)
+		if err != nil {
+			if _, rbErr := tx.Exec(ctx, "ROLLBACK TO SAVEPOINT "+savepoint); rbErr != nil {
+				return nil, rbErr
+			}
+			result.Errors = append(result.Errors, db.BulkWriteError{Index: i, Err: err})
+			continue
+		}
+
+		if _, err := tx.Exec(ctx, "RELEASE SAVEPOINT "+savepoint); err != nil {
+			return nil, err
+		}
+
+		if inserted {
+			result.UpsertedCount++
+		} else {
+			result.ModifiedCount++
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// AddGuildPortfolios upserts portfolios, keyed by (guild_id, updated_at) via
+// the unique constraint from migrations/0003_portfolio_dedup.sql, so a
+// retried snapshot is deduplicated in place. A failed document is recorded
+// on the returned BulkResult rather than aborting the rest of the batch.
+func (s *PgImpl) AddGuildPortfolios(ctx context.Context, portfolios []*model.GuildPortfolio) (*db.BulkResult, error) {
+	return s.upsertWithSavepoints(ctx, len(portfolios), func(tx pgx.Tx, i int) (bool, error) {
+		p := portfolios[i]
+		balances, err := marshalBalances(p.Balances)
+		if err != nil {
+			return false, err
+		}
+
+		var inserted bool
+		err = tx.QueryRow(ctx, `
+			INSERT INTO guild_portfolios (id, guild_id, updated_at, total_value, balances)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (guild_id, updated_at) DO UPDATE
+				SET total_value = EXCLUDED.total_value, balances = EXCLUDED.balances
+			RETURNING (xmax = 0) AS inserted
+		`, newObjectIDHex(), p.GuildID.Hex(), p.UpdatedAt, p.TotalValue, balances).Scan(&inserted)
+		return inserted, err
+	})
+}
+
+func (s *PgImpl) ListGuildPortfolios(
+	ctx context.Context,
+	filter model.GuildPortfoliosFilter,
+) (result []*model.GuildPortfolio, nextPageToken string, err error) {
+	where := []string{"guild_id = $1"}
+	args := []interface{}{filter.GuildID}
+
+	if filter.StartTime != nil {
+		args = append(args, *filter.StartTime)
+		where = append(where, fmt.Sprintf("updated_at >= $%d", len(args)))
+	}
+	if filter.EndTime != nil {
+		args = append(args, *filter.EndTime)
+		where = append(where, fmt.Sprintf("updated_at < $%d", len(args)))
+	}
+	if filter.PageToken != "" {
+		cursor, err := decodePageToken(filter.PageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, cursor.SortKey, cursor.ID)
+		where = append(where, fmt.Sprintf("(updated_at, id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	limitClause := ""
+	if filter.Limit != nil && *filter.Limit > 0 {
+		args = append(args, *filter.Limit+1)
+		limitClause = fmt.Sprintf("LIMIT $%d", len(args))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, guild_id, updated_at, total_value, balances
+		FROM guild_portfolios
+		WHERE %s
+		ORDER BY updated_at DESC, id DESC
+		%s
+	`, joinAnd(where), limitClause)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			idHex, guildIDHex string
+			rawBalances       []byte
+			portfolio         model.GuildPortfolio
+		)
+		if err := rows.Scan(&idHex, &guildIDHex, &portfolio.UpdatedAt, &portfolio.TotalValue, &rawBalances); err != nil {
+			return nil, "", err
+		}
+
+		if portfolio.ID, err = primitive.ObjectIDFromHex(idHex); err != nil {
+			return nil, "", fmt.Errorf("cannot parse portfolio id: %w", err)
+		}
+		if portfolio.GuildID, err = primitive.ObjectIDFromHex(guildIDHex); err != nil {
+			return nil, "", fmt.Errorf("cannot parse guild id: %w", err)
+		}
+		if portfolio.Balances, err = unmarshalBalances(rawBalances); err != nil {
+			return nil, "", err
+		}
+
+		result = append(result, &portfolio)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if filter.Limit != nil && *filter.Limit > 0 && int64(len(result)) > *filter.Limit {
+		last := result[*filter.Limit-1]
+		nextPageToken, err = encodePageToken(last.UpdatedAt, last.ID.Hex())
+		if err != nil {
+			return nil, "", err
+		}
+		result = result[:*filter.Limit]
+	}
+
+	return result, nextPageToken, nil
+}
+
+func (s *PgImpl) GetAccountPortfolio(ctx context.Context, address model.Address) (*model.AccountPortfolio, error) {
+	row := s.pool.QueryRow(ctx, `
+		SELECT id, injective_address, guild_id, updated_at, total_value, balances
+		FROM account_portfolios
+		WHERE injective_address = $1
+		ORDER BY updated_at DESC
+		LIMIT 1
+	`, address.String())
+
+	var (
+		idHex, guildIDHex string
+		rawBalances       []byte
+		portfolio         model.AccountPortfolio
+	)
+	if err := row.Scan(&idHex, &portfolio.InjectiveAddress, &guildIDHex, &portfolio.UpdatedAt, &portfolio.TotalValue, &rawBalances); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, db.ErrNotFound
+		}
+		return nil, err
+	}
+
+	var err error
+	if portfolio.ID, err = primitive.ObjectIDFromHex(idHex); err != nil {
+		return nil, fmt.Errorf("cannot parse portfolio id: %w", err)
+	}
+	if portfolio.GuildID, err = primitive.ObjectIDFromHex(guildIDHex); err != nil {
+		return nil, fmt.Errorf("cannot parse guild id: %w", err)
+	}
+	if portfolio.Balances, err = unmarshalBalances(rawBalances); err != nil {
+		return nil, err
+	}
+
+	return &portfolio, nil
+}
+
+// AddAccountPortfolios upserts portfolios, keyed by (injective_address,
+// updated_at); see AddGuildPortfolios.
+func (s *PgImpl) AddAccountPortfolios(ctx context.Context, portfolios []*model.AccountPortfolio) (*db.BulkResult, error) {
+	return s.upsertWithSavepoints(ctx, len(portfolios), func(tx pgx.Tx, i int) (bool, error) {
+		p := portfolios[i]
+		balances, err := marshalBalances(p.Balances)
+		if err != nil {
+			return false, err
+		}
+
+		var inserted bool
+		err = tx.QueryRow(ctx, `
+			INSERT INTO account_portfolios (id, injective_address, guild_id, updated_at, total_value, balances)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (injective_address, updated_at) DO UPDATE
+				SET total_value = EXCLUDED.total_value, balances = EXCLUDED.balances
+			RETURNING (xmax = 0) AS inserted
+		`, newObjectIDHex(), p.InjectiveAddress, p.GuildID.Hex(), p.UpdatedAt, p.TotalValue, balances).Scan(&inserted)
+		return inserted, err
+	})
+}
+
+func (s *PgImpl) ListAccountPortfolios(
+	ctx context.Context,
+	filter model.AccountPortfoliosFilter,
+) (result []*model.AccountPortfolio, nextPageToken string, err error) {
+	where := []string{"injective_address = $1"}
+	args := []interface{}{filter.InjectiveAddress.String()}
+
+	if filter.StartTime != nil {
+		args = append(args, *filter.StartTime)
+		where = append(where, fmt.Sprintf("updated_at >= $%d", len(args)))
+	}
+	if filter.EndTime != nil {
+		args = append(args, *filter.EndTime)
+		where = append(where, fmt.Sprintf("updated_at < $%d", len(args)))
+	}
+	if filter.PageToken != "" {
+		cursor, err := decodePageToken(filter.PageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, cursor.SortKey, cursor.ID)
+		where = append(where, fmt.Sprintf("(updated_at, id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	limitClause := ""
+	if filter.Limit != nil && *filter.Limit > 0 {
+		args = append(args, *filter.Limit+1)
+		limitClause = fmt.Sprintf("LIMIT $%d", len(args))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, injective_address, guild_id, updated_at, total_value, balances
+		FROM account_portfolios
+		WHERE %s
+		ORDER BY updated_at DESC, id DESC
+		%s
+	`, joinAnd(where), limitClause)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			idHex, guildIDHex string
+			rawBalances       []byte
+			portfolio         model.AccountPortfolio
+		)
+		if err := rows.Scan(&idHex, &portfolio.InjectiveAddress, &guildIDHex, &portfolio.UpdatedAt, &portfolio.TotalValue, &rawBalances); err != nil {
+			return nil, "", err
+		}
+
+		if portfolio.ID, err = primitive.ObjectIDFromHex(idHex); err != nil {
+			return nil, "", fmt.Errorf("cannot parse portfolio id: %w", err)
+		}
+		if portfolio.GuildID, err = primitive.ObjectIDFromHex(guildIDHex); err != nil {
+			return nil, "", fmt.Errorf("cannot parse guild id: %w", err)
+		}
+		if portfolio.Balances, err = unmarshalBalances(rawBalances); err != nil {
+			return nil, "", err
+		}
+
+		result = append(result, &portfolio)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if filter.Limit != nil && *filter.Limit > 0 && int64(len(result)) > *filter.Limit {
+		last := result[*filter.Limit-1]
+		nextPageToken, err = encodePageToken(last.UpdatedAt, last.ID.Hex())
+		if err != nil {
+			return nil, "", err
+		}
+		result = result[:*filter.Limit]
+	}
+
+	return result, nextPageToken, nil
+}
+
+func (s *PgImpl) GetGuildLeaderboard(
+	ctx context.Context,
+	guildID string,
+	denom string,
+	startTime, endTime time.Time,
+	topN int64,
+) ([]*model.LeaderboardEntry, error) {
+	rows, err := s.pool.Query(ctx, `
+		WITH balances AS (
+			SELECT
+				injective_address,
+				updated_at,
+				(b->>'amount')::numeric AS amount
+			FROM account_portfolios, jsonb_array_elements(balances) AS b
+			WHERE guild_id = $1 AND updated_at >= $2 AND updated_at < $3 AND b->>'denom' = $4
+		),
+		bounds AS (
+			SELECT
+				injective_address,
+				(array_agg(amount ORDER BY updated_at ASC))[1]  AS start_value,
+				(array_agg(amount ORDER BY updated_at DESC))[1] AS end_value
+			FROM balances
+			GROUP BY injective_address
+		)
+		SELECT injective_address, start_value, end_value, end_value - start_value AS change
+		FROM bounds
+		ORDER BY change DESC
+		LIMIT $5
+	`, guildID, startTime, endTime, denom, topN)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*model.LeaderboardEntry
+	for rows.Next() {
+		var (
+			entry                        model.LeaderboardEntry
+			startValue, endValue, change string
+		)
+		if err := rows.Scan(&entry.InjectiveAddress, &startValue, &endValue, &change); err != nil {
+			return nil, err
+		}
+
+		if entry.StartValue, err = primitive.ParseDecimal128(startValue); err != nil {
+			return nil, err
+		}
+		if entry.EndValue, err = primitive.ParseDecimal128(endValue); err != nil {
+			return nil, err
+		}
+		if entry.Change, err = primitive.ParseDecimal128(change); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &entry)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *PgImpl) GetGuildPortfolioSeries(
+	ctx context.Context,
+	guildID string,
+	bucket time.Duration,
+	startTime, endTime time.Time,
+) ([]*model.PortfolioBucket, error) {
+	interval := fmt.Sprintf("%d seconds", int64(bucket.Seconds()))
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT
+			date_bin($1::interval, updated_at, TIMESTAMP '2000-01-01') AS bucket_start,
+			(array_agg(total_value::numeric ORDER BY updated_at ASC))[1]  AS open,
+			MAX(total_value::numeric)                                    AS high,
+			MIN(total_value::numeric)                                    AS low,
+			(array_agg(total_value::numeric ORDER BY updated_at DESC))[1] AS close
+		FROM guild_portfolios
+		WHERE guild_id = $2 AND updated_at >= $3 AND updated_at < $4
+		GROUP BY bucket_start
+		ORDER BY bucket_start ASC
+	`, interval, guildID, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*model.PortfolioBucket
+	for rows.Next() {
+		var (
+			b                                  model.PortfolioBucket
+			openVal, highVal, lowVal, closeVal string
+		)
+		if err := rows.Scan(&b.BucketStart, &openVal, &highVal, &lowVal, &closeVal); err != nil {
+			return nil, err
+		}
+
+		if b.Open, err = primitive.ParseDecimal128(openVal); err != nil {
+			return nil, err
+		}
+		if b.High, err = primitive.ParseDecimal128(highVal); err != nil {
+			return nil, err
+		}
+		if b.Low, err = primitive.ParseDecimal128(lowVal); err != nil {
+			return nil, err
+		}
+		if b.Close, err = primitive.ParseDecimal128(closeVal); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &b)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *PgImpl) Disconnect(ctx context.Context) error {
+	s.pool.Close()
+	return nil
+}
+
+func joinAnd(clauses []string) string {
+	out := clauses[0]
+	for _, c := range clauses[1:] {
+		out += " AND " + c
+	}
+	return out
+}
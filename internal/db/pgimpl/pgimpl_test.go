@@ -0,0 +1,31 @@
+//go:build integration
+
+package pgimpl_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/InjectiveLabs/injective-guilds-service/internal/db/dbtest"
+	"github.com/InjectiveLabs/injective-guilds-service/internal/db/pgimpl"
+)
+
+// TestDBServiceSuite runs the shared db.DBService behavioral suite against a
+// real Postgres instance pointed to by POSTGRES_TEST_URI. Run with:
+// go test -tags integration ./internal/db/pgimpl/...
+func TestDBServiceSuite(t *testing.T) {
+	uri := os.Getenv("POSTGRES_TEST_URI")
+	if uri == "" {
+		t.Skip("POSTGRES_TEST_URI not set, skipping pgimpl integration suite")
+	}
+
+	ctx := context.Background()
+	svc, err := pgimpl.NewService(ctx, uri)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	defer svc.Disconnect(ctx)
+
+	dbtest.RunSuite(t, svc)
+}